@@ -6,6 +6,7 @@ import (
 	pph "github.com/prometheus/client_golang/prometheus/promhttp"
 	"net/http"
 	"strings"
+	"time"
 )
 
 // Client embeds original http Client.
@@ -17,79 +18,66 @@ type Client struct {
 }
 
 // ForRecipient allocates new client based on base one with incomingInstrumentation.
-// Given recipient is used as a constant label.
-func (c *Client) ForRecipient(recipient string) (*http.Client, error) {
-	return instrumentClientWithConstLabels(c.Namespace, c.Client, c.Registerer, map[string]string{
-		"recipient": recipient,
-	})
+// Given recipient is used as a constant label, except when WithMetrics
+// supplies the collectors (see WithMetrics). Opts can be used to customize
+// buckets, add const or per-request labels, or share metrics across clients.
+func (c *Client) ForRecipient(recipient string, opts ...Option) (*http.Client, error) {
+	cfg := newConfig(opts...)
+	cfg.constLabels["recipient"] = recipient
+	return instrumentClientWithConfig(c.Namespace, c.Client, c.Registerer, cfg)
 }
 
-func instrumentClientWithConstLabels(namespace string, c *http.Client, reg prometheus.Registerer, constLabels map[string]string) (*http.Client, error) {
-	i := &outgoingInstrumentation{
+// NewOutgoingInstrumentation builds the collectors ForRecipient uses by
+// default and registers them with reg. Share the result across multiple
+// ForRecipient clients via WithMetrics instead of letting each one register
+// its own collectors.
+func NewOutgoingInstrumentation(namespace string, reg prometheus.Registerer, opts ...Option) (*OutgoingInstrumentation, error) {
+	cfg := newConfig(opts...)
+	i := newOutgoingInstrumentation(namespace, cfg)
+	if err := reg.Register(i); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func newOutgoingInstrumentation(namespace string, cfg *config) *OutgoingInstrumentation {
+	codeMethodLabels := append([]string{"code", "method"}, extraLabelNames(cfg.extraLabels)...)
+	errorLabels := append([]string{"method", "error_class"}, extraLabelNames(cfg.extraLabels)...)
+
+	return &OutgoingInstrumentation{
 		requests: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Namespace:   namespace,
 				Subsystem:   subsystemHTTPOutgoing,
 				Name:        "requests_total",
 				Help:        "A counter for outgoing requests from the wrapped client.",
-				ConstLabels: constLabels,
+				ConstLabels: cfg.constLabels,
 			},
-			[]string{"code", "method"},
+			codeMethodLabels,
 		),
 		requestSize: prometheus.NewHistogramVec(
-			prometheus.HistogramOpts{
-				Namespace:   namespace,
-				Subsystem:   subsystemHTTPOutgoing,
-				Name:        "request_size_histogram_bytes",
-				Help:        "Request size in bytes.",
-				Buckets:     []float64{100, 1000, 2000, 5000, 10000},
-				ConstLabels: constLabels,
-			},
-			[]string{"code", "method"},
+			histogramOpts(cfg, namespace, "request_size_histogram_bytes", "Request size in bytes.",
+				bucketsFor(cfg, RequestSizeBuckets, []float64{100, 1000, 2000, 5000, 10000}), cfg.constLabels),
+			codeMethodLabels,
 		),
 		responseContentLength: prometheus.NewHistogramVec(
-			prometheus.HistogramOpts{
-
-				Namespace:   namespace,
-				Subsystem:   subsystemHTTPOutgoing,
-				Name:        "response_content_length_histogram",
-				Help:        "Response content length in bytes.",
-				Buckets:     []float64{100, 1000, 2000, 5000, 10000},
-				ConstLabels: constLabels,
-			},
-			[]string{"code", "method"},
+			histogramOpts(cfg, namespace, "response_content_length_histogram", "Response content length in bytes.",
+				bucketsFor(cfg, ResponseContentLengthBuckets, []float64{100, 1000, 2000, 5000, 10000}), cfg.constLabels),
+			codeMethodLabels,
 		),
 		duration: prometheus.NewHistogramVec(
-			prometheus.HistogramOpts{
-				Namespace:   namespace,
-				Subsystem:   subsystemHTTPOutgoing,
-				Name:        "request_duration_histogram_seconds",
-				Help:        "A histogram of outgoing request latencies.",
-				Buckets:     prometheus.DefBuckets,
-				ConstLabels: constLabels,
-			},
-			[]string{"method"},
+			histogramOpts(cfg, namespace, "request_duration_histogram_seconds", "A histogram of outgoing request latencies.",
+				bucketsFor(cfg, DurationBuckets, prometheus.DefBuckets), cfg.constLabels),
+			codeMethodLabels,
 		),
 		dnsDuration: prometheus.NewHistogramVec(
-			prometheus.HistogramOpts{
-				Namespace:   namespace,
-				Subsystem:   subsystemHTTPOutgoing,
-				Name:        "dns_duration_histogram_seconds",
-				Help:        "Trace dns latency histogram.",
-				Buckets:     []float64{.005, .01, .025, .05},
-				ConstLabels: constLabels,
-			},
+			histogramOpts(cfg, namespace, "dns_duration_histogram_seconds", "Trace dns latency histogram.",
+				bucketsFor(cfg, DNSDurationBuckets, []float64{.005, .01, .025, .05}), cfg.constLabels),
 			[]string{"event"},
 		),
 		tlsDuration: prometheus.NewHistogramVec(
-			prometheus.HistogramOpts{
-				Namespace:   namespace,
-				Subsystem:   subsystemHTTPOutgoing,
-				Name:        "tls_duration_histogram_seconds",
-				Help:        "Trace tls latency histogram.",
-				Buckets:     []float64{.05, .1, .25, .5},
-				ConstLabels: constLabels,
-			},
+			histogramOpts(cfg, namespace, "tls_duration_histogram_seconds", "Trace tls latency histogram.",
+				bucketsFor(cfg, TLSDurationBuckets, []float64{.05, .1, .25, .5}), cfg.constLabels),
 			[]string{"event"},
 		),
 		inflight: prometheus.NewGauge(prometheus.GaugeOpts{
@@ -97,8 +85,28 @@ func instrumentClientWithConstLabels(namespace string, c *http.Client, reg prome
 			Subsystem:   subsystemHTTPOutgoing,
 			Name:        "in_flight_requests",
 			Help:        "A gauge of in-flight outgoing requests for the wrapped client.",
-			ConstLabels: constLabels,
+			ConstLabels: cfg.constLabels,
 		}),
+		errors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				Subsystem:   subsystemHTTPOutgoing,
+				Name:        "errors_total",
+				Help:        "A counter for outgoing requests that failed before a response was received, by error class.",
+				ConstLabels: cfg.constLabels,
+			},
+			errorLabels,
+		),
+	}
+}
+
+func instrumentClientWithConfig(namespace string, c *http.Client, reg prometheus.Registerer, cfg *config) (*http.Client, error) {
+	i := cfg.metrics
+	if i == nil {
+		i = newOutgoingInstrumentation(namespace, cfg)
+		if err := reg.Register(i); err != nil {
+			return nil, err
+		}
 	}
 
 	trace := &pph.InstrumentTrace{
@@ -125,20 +133,93 @@ func instrumentClientWithConstLabels(namespace string, c *http.Client, reg prome
 		Jar:           c.Jar,
 		Timeout:       c.Timeout,
 		Transport: pph.InstrumentRoundTripperInFlight(i.inflight,
-			pph.InstrumentRoundTripperCounter(i.requests,
-				pph.InstrumentRoundTripperTrace(trace,
-					instrumentRoundTripperRequestSize(i.requestSize,
-						instrumentRoundTripperResponseContentLength(i.responseContentLength,
-							pph.InstrumentRoundTripperDuration(i.duration, transport),
+			instrumentRoundTripperErrors(i.errors, i.duration, cfg.extraLabels,
+				instrumentRoundTripperCounter(i.requests, cfg,
+					pph.InstrumentRoundTripperTrace(trace,
+						instrumentRoundTripperRequestSize(i.requestSize, cfg.extraLabels,
+							instrumentRoundTripperResponseContentLength(i.responseContentLength, cfg.extraLabels,
+								instrumentRoundTripperDuration(i.duration, cfg, transport),
+							),
 						),
 					),
 				),
 			),
 		),
-	}, reg.Register(i)
+	}, nil
+}
+
+// instrumentRoundTripperErrors increments errors, classified by error_class,
+// and records a duration observation with code="error" whenever next returns
+// a non-nil error, so failed requests aren't invisible in the metrics.
+func instrumentRoundTripperErrors(errs *prometheus.CounterVec, duration prometheus.ObserverVec, extraLabels []extraLabel, next http.RoundTripper) pph.RoundTripperFunc {
+	return func(r *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := next.RoundTrip(r)
+		if err != nil {
+			extra := extraLabelValues(r, extraLabels)
+
+			errLabels := prometheus.Labels{
+				"method":      strings.ToLower(r.Method),
+				"error_class": classifyError(err),
+			}
+			mergeLabels(errLabels, extra)
+			errs.With(errLabels).Inc()
+
+			durationLabels := prometheus.Labels{
+				"code":   "error",
+				"method": strings.ToLower(r.Method),
+			}
+			mergeLabels(durationLabels, extra)
+			duration.With(durationLabels).Observe(time.Since(start).Seconds())
+		}
+		return resp, err
+	}
+}
+
+func instrumentRoundTripperCounter(counter *prometheus.CounterVec, cfg *config, next http.RoundTripper) pph.RoundTripperFunc {
+	return func(r *http.Request) (*http.Response, error) {
+		resp, err := next.RoundTrip(r)
+		if err == nil {
+			labels := prometheus.Labels{
+				"code":   fmt.Sprint(resp.StatusCode),
+				"method": strings.ToLower(r.Method),
+			}
+			mergeLabels(labels, extraLabelValues(r, cfg.extraLabels))
+
+			c := counter.With(labels)
+			if exemplar := exemplarLabels(cfg, r); exemplar != nil {
+				c.(prometheus.ExemplarAdder).AddWithExemplar(1, exemplar)
+			} else {
+				c.Inc()
+			}
+		}
+		return resp, err
+	}
+}
+
+func instrumentRoundTripperDuration(obs prometheus.ObserverVec, cfg *config, next http.RoundTripper) pph.RoundTripperFunc {
+	return func(r *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := next.RoundTrip(r)
+		if err == nil {
+			labels := prometheus.Labels{
+				"code":   fmt.Sprint(resp.StatusCode),
+				"method": strings.ToLower(r.Method),
+			}
+			mergeLabels(labels, extraLabelValues(r, cfg.extraLabels))
+
+			o := obs.With(labels)
+			if exemplar := exemplarLabels(cfg, r); exemplar != nil {
+				o.(prometheus.ExemplarObserver).ObserveWithExemplar(time.Since(start).Seconds(), exemplar)
+			} else {
+				o.Observe(time.Since(start).Seconds())
+			}
+		}
+		return resp, err
+	}
 }
 
-func instrumentRoundTripperRequestSize(obs prometheus.ObserverVec, next http.RoundTripper) pph.RoundTripperFunc {
+func instrumentRoundTripperRequestSize(obs prometheus.ObserverVec, extraLabels []extraLabel, next http.RoundTripper) pph.RoundTripperFunc {
 	return func(r *http.Request) (*http.Response, error) {
 		resp, err := next.RoundTrip(r)
 		if err == nil {
@@ -146,6 +227,7 @@ func instrumentRoundTripperRequestSize(obs prometheus.ObserverVec, next http.Rou
 				"code":   fmt.Sprint(resp.StatusCode),
 				"method": strings.ToLower(r.Method),
 			}
+			mergeLabels(labels, extraLabelValues(r, extraLabels))
 
 			obs.With(labels).Observe(float64(computeApproximateRequestSize(r)))
 		}
@@ -177,7 +259,7 @@ func computeApproximateRequestSize(r *http.Request) int {
 	return s
 }
 
-func instrumentRoundTripperResponseContentLength(obs prometheus.ObserverVec, next http.RoundTripper) pph.RoundTripperFunc {
+func instrumentRoundTripperResponseContentLength(obs prometheus.ObserverVec, extraLabels []extraLabel, next http.RoundTripper) pph.RoundTripperFunc {
 	return func(r *http.Request) (*http.Response, error) {
 		resp, err := next.RoundTrip(r)
 		if err == nil {
@@ -185,6 +267,7 @@ func instrumentRoundTripperResponseContentLength(obs prometheus.ObserverVec, nex
 				"code":   fmt.Sprint(resp.StatusCode),
 				"method": strings.ToLower(r.Method),
 			}
+			mergeLabels(labels, extraLabelValues(r, extraLabels))
 
 			obs.With(labels).Observe(float64(resp.ContentLength))
 		}
@@ -192,7 +275,10 @@ func instrumentRoundTripperResponseContentLength(obs prometheus.ObserverVec, nex
 	}
 }
 
-type outgoingInstrumentation struct {
+// OutgoingInstrumentation holds the collectors registered for a ForRecipient
+// client. Build one with NewOutgoingInstrumentation to share it across
+// multiple ForRecipient clients via WithMetrics.
+type OutgoingInstrumentation struct {
 	duration              *prometheus.HistogramVec
 	requests              *prometheus.CounterVec
 	dnsDuration           *prometheus.HistogramVec
@@ -200,10 +286,11 @@ type outgoingInstrumentation struct {
 	inflight              prometheus.Gauge
 	requestSize           *prometheus.HistogramVec
 	responseContentLength *prometheus.HistogramVec
+	errors                *prometheus.CounterVec
 }
 
 // Describe implements prometheus.Collector interface.
-func (i *outgoingInstrumentation) Describe(in chan<- *prometheus.Desc) {
+func (i *OutgoingInstrumentation) Describe(in chan<- *prometheus.Desc) {
 	i.duration.Describe(in)
 	i.requests.Describe(in)
 	i.dnsDuration.Describe(in)
@@ -211,10 +298,11 @@ func (i *outgoingInstrumentation) Describe(in chan<- *prometheus.Desc) {
 	i.inflight.Describe(in)
 	i.requestSize.Describe(in)
 	i.responseContentLength.Describe(in)
+	i.errors.Describe(in)
 }
 
 // Collect implements prometheus.Collector interface.
-func (i *outgoingInstrumentation) Collect(in chan<- prometheus.Metric) {
+func (i *OutgoingInstrumentation) Collect(in chan<- prometheus.Metric) {
 	i.duration.Collect(in)
 	i.requests.Collect(in)
 	i.dnsDuration.Collect(in)
@@ -222,4 +310,5 @@ func (i *outgoingInstrumentation) Collect(in chan<- prometheus.Metric) {
 	i.inflight.Collect(in)
 	i.requestSize.Collect(in)
 	i.responseContentLength.Collect(in)
+	i.errors.Collect(in)
 }
@@ -0,0 +1,94 @@
+package promhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestForRecipientSharedMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	reg := prometheus.NewRegistry()
+	metrics, err := NewOutgoingInstrumentation("app", reg, WithPathLabelFunc("path", FixedPathsFunc([]string{"/hello"})))
+	if err != nil {
+		t.Fatalf("NewOutgoingInstrumentation() error = %v", err)
+	}
+
+	a := &Client{Client: &http.Client{}, Namespace: "app", Registerer: reg}
+	b := &Client{Client: &http.Client{}, Namespace: "app", Registerer: reg}
+
+	opts := []Option{WithMetrics(metrics), WithPathLabelFunc("path", FixedPathsFunc([]string{"/hello"}))}
+
+	clientA, err := a.ForRecipient("svc-a", opts...)
+	if err != nil {
+		t.Fatalf("ForRecipient(svc-a) error = %v", err)
+	}
+	clientB, err := b.ForRecipient("svc-b", opts...)
+	if err != nil {
+		t.Fatalf("ForRecipient(svc-b) error = %v", err)
+	}
+
+	for _, c := range []*http.Client{clientA, clientB} {
+		resp, err := c.Get(srv.URL + "/hello")
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	labels := prometheus.Labels{"code": "200", "method": "get", "path": "/hello"}
+	if got, want := testutil.ToFloat64(metrics.requests.With(labels)), float64(2); got != want {
+		t.Errorf("requests_total{%v} = %v, want %v (both ForRecipient clients should share the same collectors)", labels, got, want)
+	}
+}
+
+func TestForRecipientBuckets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	reg := prometheus.NewRegistry()
+	c := &Client{Client: &http.Client{}, Namespace: "app", Registerer: reg}
+	instrumented, err := c.ForRecipient("svc", WithBuckets(DurationBuckets, []float64{1, 2, 3}))
+	if err != nil {
+		t.Fatalf("ForRecipient() error = %v", err)
+	}
+	resp, err := instrumented.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	mf, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+
+	var found bool
+	for _, m := range mf {
+		if m.GetName() != "app_http_outgoing_request_duration_histogram_seconds" {
+			continue
+		}
+		found = true
+		buckets := m.GetMetric()[0].GetHistogram().GetBucket()
+		if len(buckets) != 3 {
+			t.Fatalf("got %d buckets, want 3", len(buckets))
+		}
+		for i, want := range []float64{1, 2, 3} {
+			if got := buckets[i].GetUpperBound(); got != want {
+				t.Errorf("bucket[%d] = %v, want %v", i, got, want)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("duration histogram not found in registry")
+	}
+}
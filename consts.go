@@ -0,0 +1,6 @@
+package promhttp
+
+const (
+	subsystemHTTPOutgoing = "http_outgoing"
+	subsystemHTTPIncoming = "http_incoming"
+)
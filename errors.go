@@ -0,0 +1,54 @@
+package promhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"syscall"
+)
+
+// classifyError buckets a RoundTrip error into a low-cardinality class for
+// the errors_total counter, so timeouts, cancellations, DNS failures, TLS
+// errors, and refused connections can be distinguished without exploding on
+// err.Error(). Anything else, connection resets included, is "other".
+func classifyError(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection_refused"
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsTimeout {
+			return "timeout"
+		}
+		return "dns"
+	}
+
+	var headerErr tls.RecordHeaderError
+	if errors.As(err, &headerErr) {
+		return "tls"
+	}
+	var certInvalidErr x509.CertificateInvalidError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	if errors.As(err, &certInvalidErr) || errors.As(err, &unknownAuthorityErr) || errors.As(err, &hostnameErr) {
+		return "tls"
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		if opErr.Timeout() {
+			return "timeout"
+		}
+		return "other"
+	}
+
+	return "other"
+}
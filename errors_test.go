@@ -0,0 +1,49 @@
+package promhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"deadline exceeded", context.DeadlineExceeded, "timeout"},
+		{"wrapped deadline exceeded", fmt.Errorf("round trip: %w", context.DeadlineExceeded), "timeout"},
+		{"canceled", context.Canceled, "canceled"},
+		{"connection refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, "connection_refused"},
+		{"dns error", &net.DNSError{Err: "no such host"}, "dns"},
+		{"dns timeout", &net.DNSError{Err: "timeout", IsTimeout: true}, "timeout"},
+		{"op error timeout", &net.OpError{Op: "read", Err: errOpTimeout{}}, "timeout"},
+		{"op error other", &net.OpError{Op: "read", Err: errors.New("boom")}, "other"},
+		{"tls record header error", tls.RecordHeaderError{Msg: "bad header"}, "tls"},
+		{"tls unknown authority", x509.UnknownAuthorityError{}, "tls"},
+		{"tls hostname error", x509.HostnameError{}, "tls"},
+		{"other", errors.New("something else"), "other"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := classifyError(tc.err); got != tc.want {
+				t.Errorf("classifyError(%v) = %q, want %q", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+// errOpTimeout implements net.Error with Timeout() == true, for exercising
+// the net.OpError.Timeout() branch of classifyError.
+type errOpTimeout struct{}
+
+func (errOpTimeout) Error() string   { return "i/o timeout" }
+func (errOpTimeout) Timeout() bool   { return true }
+func (errOpTimeout) Temporary() bool { return true }
@@ -0,0 +1,206 @@
+package promhttp
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Bucket set names accepted by WithBuckets, matching the histograms built by
+// newOutgoingInstrumentation.
+const (
+	RequestSizeBuckets           = "request_size"
+	ResponseContentLengthBuckets = "response_content_length"
+	DurationBuckets              = "duration"
+	DNSDurationBuckets           = "dns_duration"
+	TLSDurationBuckets           = "tls_duration"
+)
+
+// Option configures the instrumentation installed by Client.ForRecipient.
+type Option func(*config)
+
+// extraLabel pairs a label name with the function used to resolve its value
+// from the outgoing request at RoundTrip time.
+type extraLabel struct {
+	name string
+	fn   func(*http.Request) string
+}
+
+// PathLabelFunc extracts a low-cardinality label value (e.g. a route
+// template) from an outgoing request, for use with WithPathLabelFunc.
+type PathLabelFunc func(*http.Request) string
+
+// nativeHistogramConfig carries the sparse-bucket settings from
+// WithNativeHistograms through to each HistogramOpts built by
+// newOutgoingInstrumentation.
+type nativeHistogramConfig struct {
+	factor           float64
+	maxBucketNumber  uint32
+	minResetDuration time.Duration
+}
+
+type config struct {
+	buckets         map[string][]float64
+	constLabels     map[string]string
+	extraLabels     []extraLabel
+	metrics         *OutgoingInstrumentation
+	nativeHistogram *nativeHistogramConfig
+	exemplarFromCtx func(context.Context) prometheus.Labels
+}
+
+func newConfig(opts ...Option) *config {
+	c := &config{constLabels: map[string]string{}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithBuckets overrides the histogram buckets used for the given bucket set
+// (one of RequestSizeBuckets, ResponseContentLengthBuckets, DurationBuckets,
+// DNSDurationBuckets or TLSDurationBuckets).
+func WithBuckets(name string, buckets []float64) Option {
+	return func(c *config) {
+		if c.buckets == nil {
+			c.buckets = map[string][]float64{}
+		}
+		c.buckets[name] = buckets
+	}
+}
+
+// WithConstLabels adds constant labels to every metric emitted by the
+// instrumented client, in addition to the recipient label ForRecipient
+// already sets.
+func WithConstLabels(labels map[string]string) Option {
+	return func(c *config) {
+		for k, v := range labels {
+			c.constLabels[k] = v
+		}
+	}
+}
+
+// WithLabelFromContext adds a label populated at RoundTrip time by calling
+// fn with the request's context. It is useful for labels such as tenant or
+// route template that are only known once a request is in flight.
+func WithLabelFromContext(label string, fn func(context.Context) string) Option {
+	return func(c *config) {
+		c.extraLabels = append(c.extraLabels, extraLabel{
+			name: label,
+			fn:   func(r *http.Request) string { return fn(r.Context()) },
+		})
+	}
+}
+
+// WithPathLabelFunc adds a label populated at RoundTrip time by calling fn
+// with the outgoing request, under the given label name. It is meant for
+// low-cardinality values such as a templated route, since raw URL paths are
+// usually unbounded. See StripIDsPathFunc and FixedPathsFunc for built-in
+// extractors.
+func WithPathLabelFunc(label string, fn PathLabelFunc) Option {
+	return func(c *config) {
+		c.extraLabels = append(c.extraLabels, extraLabel{name: label, fn: fn})
+	}
+}
+
+// WithMetrics supplies an OutgoingInstrumentation built via
+// NewOutgoingInstrumentation instead of having ForRecipient build and
+// register its own, letting callers share one set of collectors across
+// multiple ForRecipient clients. The supplied collectors are used as they were
+// built: the recipient label and any buckets, const labels or native histogram
+// settings passed to ForRecipient are not applied to them.
+func WithMetrics(metrics *OutgoingInstrumentation) Option {
+	return func(c *config) {
+		c.metrics = metrics
+	}
+}
+
+// WithNativeHistograms switches every histogram built by ForRecipient from
+// classic fixed buckets to Prometheus native (sparse) histograms, so exposed
+// resolution no longer depends on a hand-picked bucket set. See HistogramOpts
+// in the prometheus client for the meaning of factor, maxBucketNumber and
+// minResetDuration.
+func WithNativeHistograms(factor float64, maxBucketNumber uint32, minResetDuration time.Duration) Option {
+	return func(c *config) {
+		c.nativeHistogram = &nativeHistogramConfig{
+			factor:           factor,
+			maxBucketNumber:  maxBucketNumber,
+			minResetDuration: minResetDuration,
+		}
+	}
+}
+
+// WithExemplarsFromContext attaches an exemplar to the requests counter and
+// duration histogram, resolved at RoundTrip time by calling fn with the
+// request's context. This is the hook for piping e.g. an OpenTelemetry
+// trace_id/span_id into Prometheus exemplars.
+func WithExemplarsFromContext(fn func(context.Context) prometheus.Labels) Option {
+	return func(c *config) {
+		c.exemplarFromCtx = fn
+	}
+}
+
+func bucketsFor(c *config, name string, def []float64) []float64 {
+	if b, ok := c.buckets[name]; ok {
+		return b
+	}
+	return def
+}
+
+// histogramOpts builds HistogramOpts shared by every histogram ForRecipient
+// registers, applying native histogram settings when WithNativeHistograms was
+// used.
+func histogramOpts(c *config, namespace, name, help string, buckets []float64, constLabels map[string]string) prometheus.HistogramOpts {
+	opts := prometheus.HistogramOpts{
+		Namespace:   namespace,
+		Subsystem:   subsystemHTTPOutgoing,
+		Name:        name,
+		Help:        help,
+		Buckets:     buckets,
+		ConstLabels: constLabels,
+	}
+	if nh := c.nativeHistogram; nh != nil {
+		opts.NativeHistogramBucketFactor = nh.factor
+		opts.NativeHistogramMaxBucketNumber = nh.maxBucketNumber
+		opts.NativeHistogramMinResetDuration = nh.minResetDuration
+		if nh.factor > 1 {
+			opts.Buckets = nil
+		}
+	}
+	return opts
+}
+
+// exemplarLabels resolves the configured exemplar, if any, for r.
+func exemplarLabels(c *config, r *http.Request) prometheus.Labels {
+	if c.exemplarFromCtx == nil {
+		return nil
+	}
+	return c.exemplarFromCtx(r.Context())
+}
+
+func extraLabelNames(labels []extraLabel) []string {
+	names := make([]string, 0, len(labels))
+	for _, l := range labels {
+		names = append(names, l.name)
+	}
+	return names
+}
+
+func extraLabelValues(r *http.Request, labels []extraLabel) prometheus.Labels {
+	if len(labels) == 0 {
+		return nil
+	}
+	values := make(prometheus.Labels, len(labels))
+	for _, l := range labels {
+		values[l.name] = l.fn(r)
+	}
+	return values
+}
+
+func mergeLabels(dst, src prometheus.Labels) prometheus.Labels {
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
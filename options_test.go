@@ -0,0 +1,80 @@
+package promhttp
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestNewConfigDefaults(t *testing.T) {
+	cfg := newConfig()
+	if cfg.constLabels == nil {
+		t.Fatal("constLabels should be initialized, not nil")
+	}
+	if len(cfg.constLabels) != 0 {
+		t.Fatalf("constLabels = %v, want empty", cfg.constLabels)
+	}
+	if cfg.metrics != nil {
+		t.Fatal("metrics should be nil by default")
+	}
+}
+
+func TestWithBuckets(t *testing.T) {
+	cfg := newConfig(WithBuckets(DurationBuckets, []float64{1, 2, 3}))
+	got := bucketsFor(cfg, DurationBuckets, prometheus.DefBuckets)
+	want := []float64{1, 2, 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("bucketsFor(DurationBuckets) = %v, want %v", got, want)
+	}
+
+	// An unset bucket name falls back to the provided default.
+	got = bucketsFor(cfg, RequestSizeBuckets, []float64{100, 200})
+	want = []float64{100, 200}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("bucketsFor(RequestSizeBuckets) = %v, want %v", got, want)
+	}
+}
+
+func TestWithConstLabels(t *testing.T) {
+	cfg := newConfig(WithConstLabels(map[string]string{"env": "prod"}), WithConstLabels(map[string]string{"team": "core"}))
+	want := map[string]string{"env": "prod", "team": "core"}
+	if !reflect.DeepEqual(cfg.constLabels, want) {
+		t.Errorf("constLabels = %v, want %v", cfg.constLabels, want)
+	}
+}
+
+func TestWithLabelFromContextAndPathLabelFunc(t *testing.T) {
+	cfg := newConfig(
+		WithLabelFromContext("tenant", func(ctx context.Context) string {
+			return ctx.Value("tenant").(string)
+		}),
+		WithPathLabelFunc("path", StripIDsPathFunc),
+	)
+
+	if got, want := extraLabelNames(cfg.extraLabels), []string{"tenant", "path"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("extraLabelNames = %v, want %v", got, want)
+	}
+
+	ctx := context.WithValue(context.Background(), "tenant", "acme")
+	r := &http.Request{URL: &url.URL{Path: "/users/42"}}
+	r = r.WithContext(ctx)
+
+	got := extraLabelValues(r, cfg.extraLabels)
+	want := prometheus.Labels{"tenant": "acme", "path": "/users/:id"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("extraLabelValues = %v, want %v", got, want)
+	}
+}
+
+func TestMergeLabels(t *testing.T) {
+	dst := prometheus.Labels{"a": "1"}
+	mergeLabels(dst, prometheus.Labels{"b": "2"})
+	want := prometheus.Labels{"a": "1", "b": "2"}
+	if !reflect.DeepEqual(dst, want) {
+		t.Errorf("mergeLabels result = %v, want %v", dst, want)
+	}
+}
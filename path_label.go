@@ -0,0 +1,38 @@
+package promhttp
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var idSegmentPattern = regexp.MustCompile(`(?i)^(?:[0-9]+|[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})$`)
+
+// StripIDsPathFunc is a PathLabelFunc that replaces numeric and UUID path
+// segments with ":id", turning e.g. "/users/123/orders/456" into
+// "/users/:id/orders/:id".
+func StripIDsPathFunc(r *http.Request) string {
+	segments := strings.Split(r.URL.Path, "/")
+	for i, segment := range segments {
+		if idSegmentPattern.MatchString(segment) {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// FixedPathsFunc returns a PathLabelFunc that emits the request path
+// unchanged when it is one of allowed, and "other" otherwise. It bounds
+// label cardinality to a known set of route templates.
+func FixedPathsFunc(allowed []string) PathLabelFunc {
+	allowedSet := make(map[string]struct{}, len(allowed))
+	for _, path := range allowed {
+		allowedSet[path] = struct{}{}
+	}
+	return func(r *http.Request) string {
+		if _, ok := allowedSet[r.URL.Path]; ok {
+			return r.URL.Path
+		}
+		return "other"
+	}
+}
@@ -0,0 +1,46 @@
+package promhttp
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestStripIDsPathFunc(t *testing.T) {
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"/users/123/orders/456", "/users/:id/orders/:id"},
+		{"/users/123e4567-e89b-12d3-a456-426614174000", "/users/:id"},
+		{"/health", "/health"},
+		{"/", "/"},
+	}
+
+	for _, tc := range cases {
+		r := &http.Request{URL: &url.URL{Path: tc.path}}
+		if got := StripIDsPathFunc(r); got != tc.want {
+			t.Errorf("StripIDsPathFunc(%q) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestFixedPathsFunc(t *testing.T) {
+	fn := FixedPathsFunc([]string{"/users", "/orders"})
+
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"/users", "/users"},
+		{"/orders", "/orders"},
+		{"/users/123", "other"},
+	}
+
+	for _, tc := range cases {
+		r := &http.Request{URL: &url.URL{Path: tc.path}}
+		if got := fn(r); got != tc.want {
+			t.Errorf("FixedPathsFunc(...)(%q) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+}
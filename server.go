@@ -0,0 +1,124 @@
+package promhttp
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	pph "github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Server instruments http.Handlers for incoming traffic.
+type Server struct {
+	Registerer prometheus.Registerer
+	Namespace  string
+}
+
+// ForHandler wraps next with incomingInstrumentation for the given handler
+// name, which is used as a constant label.
+func (s *Server) ForHandler(name string, next http.Handler) (http.Handler, error) {
+	mw, err := s.Middleware(name)
+	if err != nil {
+		return nil, err
+	}
+	return mw(next), nil
+}
+
+// Middleware builds incomingInstrumentation for the given handler name and
+// returns a func that wraps any number of http.Handlers with it.
+func (s *Server) Middleware(name string) (func(http.Handler) http.Handler, error) {
+	constLabels := map[string]string{"handler": name}
+
+	i := &incomingInstrumentation{
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace:   s.Namespace,
+				Subsystem:   subsystemHTTPIncoming,
+				Name:        "requests_total",
+				Help:        "A counter for incoming requests to the wrapped handler.",
+				ConstLabels: constLabels,
+			},
+			[]string{"code", "method"},
+		),
+		duration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   s.Namespace,
+				Subsystem:   subsystemHTTPIncoming,
+				Name:        "request_duration_histogram_seconds",
+				Help:        "A histogram of incoming request latencies.",
+				Buckets:     prometheus.DefBuckets,
+				ConstLabels: constLabels,
+			},
+			[]string{"code", "method"},
+		),
+		requestSize: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   s.Namespace,
+				Subsystem:   subsystemHTTPIncoming,
+				Name:        "request_size_histogram_bytes",
+				Help:        "Request size in bytes.",
+				Buckets:     []float64{100, 1000, 2000, 5000, 10000},
+				ConstLabels: constLabels,
+			},
+			[]string{"code", "method"},
+		),
+		responseSize: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace:   s.Namespace,
+				Subsystem:   subsystemHTTPIncoming,
+				Name:        "response_size_histogram_bytes",
+				Help:        "Response size in bytes.",
+				Buckets:     []float64{100, 1000, 2000, 5000, 10000},
+				ConstLabels: constLabels,
+			},
+			[]string{"code", "method"},
+		),
+		inflight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   s.Namespace,
+			Subsystem:   subsystemHTTPIncoming,
+			Name:        "in_flight_requests",
+			Help:        "A gauge of in-flight incoming requests for the wrapped handler.",
+			ConstLabels: constLabels,
+		}),
+	}
+	if err := s.Registerer.Register(i); err != nil {
+		return nil, err
+	}
+
+	return func(next http.Handler) http.Handler {
+		return pph.InstrumentHandlerInFlight(i.inflight,
+			pph.InstrumentHandlerCounter(i.requests,
+				pph.InstrumentHandlerDuration(i.duration,
+					pph.InstrumentHandlerRequestSize(i.requestSize,
+						pph.InstrumentHandlerResponseSize(i.responseSize, next),
+					),
+				),
+			),
+		)
+	}, nil
+}
+
+type incomingInstrumentation struct {
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	requestSize  *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+	inflight     prometheus.Gauge
+}
+
+// Describe implements prometheus.Collector interface.
+func (i *incomingInstrumentation) Describe(in chan<- *prometheus.Desc) {
+	i.requests.Describe(in)
+	i.duration.Describe(in)
+	i.requestSize.Describe(in)
+	i.responseSize.Describe(in)
+	i.inflight.Describe(in)
+}
+
+// Collect implements prometheus.Collector interface.
+func (i *incomingInstrumentation) Collect(in chan<- prometheus.Metric) {
+	i.requests.Collect(in)
+	i.duration.Collect(in)
+	i.requestSize.Collect(in)
+	i.responseSize.Collect(in)
+	i.inflight.Collect(in)
+}